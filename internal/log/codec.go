@@ -0,0 +1,83 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+
+	api "github.com/JINs-software/GoLogDB/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec은 segment가 레코드를 바이트로 직렬화/역직렬화할 때 사용하는 전략을 추상화한다.
+// 이전까지는 segment가 proto.Marshal/Unmarshal을 직접 호출해 저장 계층이 protobuf에
+// 결합돼 있었는데, Codec을 두면 JSON 이벤트나 원시 바이트 같은 비-proto 페이로드도
+// api.Record인 척하지 않고 그대로 저장할 수 있다.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	Name() string
+}
+
+// ProtoCodec은 proto.Message를 구현하는 값을 protobuf로 직렬화한다.
+// 기존 동작과 호환되도록 Config의 기본 Codec으로 쓰인다.
+type ProtoCodec struct{}
+
+func (ProtoCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("log: ProtoCodec: %T는 proto.Message가 아님", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (ProtoCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("log: ProtoCodec: %T는 proto.Message가 아님", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+func (ProtoCodec) Name() string { return "proto" }
+
+// JSONCodec은 임의의 Go 값을 encoding/json으로 직렬화한다. api.Record가 아닌
+// 사용자 정의 이벤트(JSON 등)를 로그에 그대로 저장하고 싶을 때 사용한다.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+func (JSONCodec) Name() string { return "json" }
+
+// RawCodec은 바이트를 변환 없이 그대로 저장/반환한다. v는 []byte, *[]byte, 또는
+// *api.Record여야 한다 - segment.Append/Read는 항상 *api.Record를 주고받으므로,
+// *api.Record가 오면 그 Value 필드만 원시 바이트로 저장/복원한다(다른 필드는 버려짐).
+type RawCodec struct{}
+
+func (RawCodec) Marshal(v any) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case *[]byte:
+		return *b, nil
+	case *api.Record:
+		return b.Value, nil
+	default:
+		return nil, fmt.Errorf("log: RawCodec: %T는 []byte/api.Record가 아님", v)
+	}
+}
+
+func (RawCodec) Unmarshal(data []byte, v any) error {
+	switch out := v.(type) {
+	case *[]byte:
+		*out = append((*out)[:0], data...)
+	case *api.Record:
+		out.Value = append(out.Value[:0], data...)
+	default:
+		return fmt.Errorf("log: RawCodec: %T는 *[]byte/*api.Record가 아님", v)
+	}
+	return nil
+}
+
+func (RawCodec) Name() string { return "raw" }
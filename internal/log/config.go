@@ -1,10 +1,37 @@
 package log
 
+import "github.com/hashicorp/raft"
+
 // store와 index 자료형을 위한 값을 가짐
 type Config struct {
+	// Codec은 segment가 레코드를 직렬화할 때 사용하는 코덱. nil이면 기존 동작과의
+	// 호환을 위해 ProtoCodec을 기본값으로 사용한다.
+	Codec Codec
+	Raft  struct {
+		raft.Config
+		StreamLayer *StreamLayer
+		// Bootstrap은 이 서버가 클러스터의 첫 서버로서 스스로를 유일한 보팅 멤버로 등록해야 하는지 여부
+		Bootstrap bool
+	}
 	Segment struct {
 		MaxStoreBytes uint64
 		MaxIndexBytes uint64
 		InitialOffset uint64
+		// MaxFileBytes는 store를 이루는 물리 파일 하나의 최대 크기. MaxStoreBytes보다
+		// 작게 잡으면 세그먼트 하나가 여러 store 파일로 쪼개져 저장된다. 0이면 store가
+		// 기본값(1GiB)을 사용한다.
+		MaxFileBytes uint64
+		// Repair가 true면 NewLog가 세그먼트의 store 파일을 열 때마다 레코드 프레이밍과
+		// CRC를 처음부터 훑어, 비정상 종료로 인해 깨지거나 잘린(torn) 마지막 레코드를
+		// 찾아 그 직전 경계로 파일을 truncate한다. 기본값(false)은 기존 동작과 같다.
+		Repair bool
+	}
+	// Sync는 store의 group-commit 고루틴이 언제 fsync를 호출해 쓰기를 안정적인
+	// 저장소에 반영할지를 결정한다. 기본값(SyncNone)은 이전과 동일하게 Read/Close
+	// 시점에만 버퍼가 flush된다.
+	Sync struct {
+		Mode          SyncMode
+		IntervalMs    int
+		MaxBatchBytes uint64
 	}
 }
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	api "github.com/JINs-software/GoLogDB/api/v1"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFSMApplyAppend은 raft.Log가 Apply될 때 FSM이 페이로드를 디코딩해 로컬 Log에
+// Append하고, 할당된 오프셋을 담은 ProduceResponse를 리턴하는지 검증한다.
+func TestFSMApplyAppend(t *testing.T) {
+	dir := t.TempDir()
+	log, err := NewLog(dir, Config{})
+	require.NoError(t, err)
+	fsm := NewFSM(log)
+
+	req := &api.ProduceRequest{Record: &api.Record{Value: []byte("hello")}}
+	b, err := (ProtoCodec{}).Marshal(req)
+	require.NoError(t, err)
+	data := append([]byte{byte(AppendRequestType)}, b...)
+
+	res := fsm.Apply(&raft.Log{Data: data})
+	produceRes, ok := res.(*api.ProduceResponse)
+	require.True(t, ok)
+	require.Equal(t, uint64(0), produceRes.Offset)
+
+	record, err := log.Read(0)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), record.Value)
+}
+
+// TestFSMSnapshotRestore는 Snapshot이 로컬 Log를 바이트 스트림으로 내보내고,
+// 그 스트림을 Restore에 먹이면 새 Log가 같은 레코드들을 같은 순서로 복원하는지 검증한다.
+// 세그먼트가 여러 개로 나뉘도록 작은 MaxStoreBytes를 써서, 스냅샷이 세그먼트 경계를
+// 정확히 이어 붙이는지도 함께 확인한다.
+func TestFSMSnapshotRestore(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 64
+	log, err := NewLog(dir, c)
+	require.NoError(t, err)
+	fsm := NewFSM(log)
+
+	for i := 0; i < 5; i++ {
+		_, err := log.Append(&api.Record{Value: []byte("record")})
+		require.NoError(t, err)
+	}
+
+	snap, err := fsm.Snapshot()
+	require.NoError(t, err)
+
+	sink := &fakeSnapshotSink{}
+	require.NoError(t, snap.Persist(sink))
+
+	restoreDir := t.TempDir()
+	restoreLog, err := NewLog(restoreDir, c)
+	require.NoError(t, err)
+	restoreFSM := NewFSM(restoreLog)
+	require.NoError(t, restoreFSM.Restore(sink))
+
+	for off := uint64(0); off < 5; off++ {
+		record, err := restoreLog.Read(off)
+		require.NoError(t, err)
+		require.Equal(t, []byte("record"), record.Value)
+	}
+}
+
+// TestDistributedLogSingleNode은 단일 노드로 부트스트랩된 DistributedLog가 리더를
+// 선출하고, Raft를 거쳐 Append된 레코드를 로컬에서 Read로 되읽을 수 있는지 검증한다.
+func TestDistributedLogSingleNode(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var config Config
+	config.Raft.StreamLayer = NewStreamLayer(ln, nil, nil)
+	config.Raft.LocalID = raft.ServerID("1")
+	config.Raft.HeartbeatTimeout = 50 * time.Millisecond
+	config.Raft.ElectionTimeout = 50 * time.Millisecond
+	config.Raft.LeaderLeaseTimeout = 50 * time.Millisecond
+	config.Raft.CommitTimeout = 5 * time.Millisecond
+	config.Raft.Bootstrap = true
+
+	dlog, err := NewDistributedLog(t.TempDir(), config)
+	require.NoError(t, err)
+	require.NoError(t, dlog.WaitForLeader(3*time.Second))
+	defer dlog.Close()
+
+	off, err := dlog.Append(&api.Record{Value: []byte("hello raft")})
+	require.NoError(t, err)
+
+	record, err := dlog.Read(off)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello raft"), record.Value)
+}
+
+// fakeSnapshotSink은 raft.SnapshotSink을 메모리 버퍼로 흉내낸다(테스트 전용).
+type fakeSnapshotSink struct {
+	buf []byte
+}
+
+func (s *fakeSnapshotSink) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+	return len(p), nil
+}
+func (s *fakeSnapshotSink) Read(p []byte) (int, error) {
+	if len(s.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+func (s *fakeSnapshotSink) Close() error  { return nil }
+func (s *fakeSnapshotSink) ID() string    { return "fake" }
+func (s *fakeSnapshotSink) Cancel() error { return nil }
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	api "github.com/JINs-software/GoLogDB/api/v1"
+	"github.com/hashicorp/raft"
+	"google.golang.org/protobuf/proto"
+)
+
+// RequestType은 Raft 로그 엔트리의 맨 앞 바이트에 실려, Apply가 엔트리를
+// 어떻게 디코딩해야 할지 구분하게 해준다. 지금은 AppendRequest 하나뿐이다.
+type RequestType uint8
+
+const (
+	AppendRequestType RequestType = 0
+)
+
+// FSM은 hashicorp/raft가 커밋한 로그 엔트리를 로컬 Log에 반영하는
+// 유한 상태 기계(finite-state machine)이다. 모든 복제본이 같은 순서로
+// Apply를 호출하기 때문에, 복제본들의 로컬 Log는 서로 같은 내용을 갖게 된다.
+type FSM struct {
+	log *Log
+}
+
+func NewFSM(log *Log) *FSM {
+	return &FSM{log: log}
+}
+
+// Apply는 raft.Log의 Data를 디코딩해 로컬 Log에 Append하고, 그 결과 오프셋을
+// *api.ProduceResponse에 담아 리턴한다(실패하면 error를 리턴). 반환값은 raft.ApplyFuture.Response()로 전달된다.
+func (f *FSM) Apply(record *raft.Log) interface{} {
+	buf := record.Data
+	reqType := RequestType(buf[0])
+	switch reqType {
+	case AppendRequestType:
+		return f.applyAppend(buf[1:])
+	}
+	return nil
+}
+
+func (f *FSM) applyAppend(b []byte) interface{} {
+	var req api.ProduceRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		return err
+	}
+	offset, err := f.log.Append(req.Record)
+	if err != nil {
+		return err
+	}
+	return &api.ProduceResponse{Offset: offset}
+}
+
+// Snapshot은 로컬 Log 전체를 바이트 스트림으로 읽어내는 fsmSnapshot을 만든다.
+// Raft가 로그 압축(log compaction)을 수행할 때 이 스냅샷을 각 팔로워에 전송한다.
+func (f *FSM) Snapshot() (raft.FSMSnapshot, error) {
+	r := f.log.Reader()
+	return &fsmSnapshot{reader: r}, nil
+}
+
+// Restore는 스냅샷 바이트를 읽어 Log를 비우고 다시 채운다. store.Append가 기록한
+// [길이 uint64][crc32c uint32][페이로드] 프레이밍을 그대로 따라가며 레코드 단위로
+// 복원하고, 복원하는 레코드마다 crc32c를 검증해 전송 중 손상을 걸러낸다.
+func (f *FSM) Restore(r io.ReadCloser) error {
+	header := make([]byte, lenWidth+crcWidth)
+	var buf bytes.Buffer
+	if err := f.log.Reset(); err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		_, err := io.ReadFull(r, header)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		size := int64(enc.Uint64(header[:lenWidth]))
+		wantCRC := enc.Uint32(header[lenWidth:])
+		if _, err = io.CopyN(&buf, r, size); err != nil {
+			return err
+		}
+		if got := crc32.Checksum(buf.Bytes(), crcTable); got != wantCRC {
+			return fmt.Errorf("log: 스냅샷 복원 중 crc 불일치 (레코드 #%d)", i)
+		}
+		record := &api.Record{}
+		if err = proto.Unmarshal(buf.Bytes(), record); err != nil {
+			return err
+		}
+		if i == 0 {
+			f.log.Config.Segment.InitialOffset = record.Offset
+			if err := f.log.Reset(); err != nil {
+				return err
+			}
+		}
+		if _, err = f.log.Append(record); err != nil {
+			return err
+		}
+		buf.Reset()
+	}
+	return nil
+}
+
+var _ raft.FSMSnapshot = (*fsmSnapshot)(nil)
+
+type fsmSnapshot struct {
+	reader io.Reader
+}
+
+// Persist는 FSM 스냅샷 바이트를 raft.SnapshotSink(디스크 또는 네트워크)로 그대로 복사한다.
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	w := bufio.NewWriter(sink)
+	if _, err := io.Copy(w, s.reader); err != nil {
+		_ = sink.Cancel()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 
@@ -8,26 +10,46 @@ import (
 )
 
 // index 코드 내 사용되는 상수들
-// 인덱스 항목은 '레코드 오프셋'과 '스토어 파일에서의 위치'라는 두 필드로 구성
+// 인덱스 항목은 '레코드 오프셋', '스토어의 어느 물리 파일인지(fileNo)',
+// '그 파일 안에서의 위치', 그리고 앞의 세 필드를 덮는 crc32c라는 네 필드로 구성된다.
+// store가 여러 파일로 쪼개지므로 파일 번호를 모르면 위치만으로는 레코드를 찾을 수 없고,
+// crc32c는 mmap에 쓰다가 프로세스가 죽어 항목이 반쯤만 반영된(torn write) 경우를 잡아낸다.
 var (
-	offWidth uint64 = 4 // 레코드의 오프셋 정보 uint32 4바이트 - 즉 몇 번째인지
-	posWidth uint64 = 8 // 위치(position) 정보 uint64 8바이트 - 즉 정확한 위치
-	entWidth        = offWidth + posWidth
+	offWidth    uint64 = 4 // 레코드의 오프셋 정보 uint32 4바이트 - 즉 몇 번째인지
+	fileNoWidth uint64 = 4 // store의 물리 파일 번호 uint32 4바이트
+	posWidth    uint64 = 8 // 위치(position) 정보 uint64 8바이트 - 파일 안에서의 정확한 위치
+	idxCrcWidth uint64 = 4 // 앞의 세 필드를 덮는 crc32c 4바이트
+	entBodyWidth       = offWidth + fileNoWidth + posWidth
+	entWidth           = entBodyWidth + idxCrcWidth
+)
+
+const (
+	idxVersionWidth uint64 = 1 // 인덱스 파일 맨 앞에 박아두는 포맷 버전 바이트의 폭
+
+	// idxVersion은 현재 인덱스 항목 포맷의 버전. fileNo와 entry crc32c가 추가되며
+	// 항목 폭이 12바이트에서 entWidth(20바이트)로 바뀌었으므로, 버전 바이트가 없는
+	// 이전 포맷 파일을 이 폭으로 잘못 재해석하지 않도록 버전을 못 박아 구분한다.
+	// 이 포맷으로 쓰인 적 없는(버전 바이트가 없는) 인덱스 파일은 호환되지 않는다 -
+	// 세그먼트를 다시 만들어야 한다.
+	idxVersion byte = 1
 )
 
 // [index]
 // 인덱스 파일을 정의하며, 파일과 메모리 맵 파일로 구성됨
-// size는 인덱스의 크기로, 인덱스에 다음 항목을 추가할 위치를 의미
+// size는 인덱스의 크기로, 인덱스에 다음 항목을 추가할 위치를 의미(버전 헤더는 제외)
 type index struct {
-	file   *os.File
-	mmap   gommap.MMap
-	size   uint64
-	config Config
+	file      *os.File
+	mmap      gommap.MMap
+	size      uint64
+	dataStart uint64
+	config    Config
 }
 
 // 'f' 파일을 위한 인덱스를 생성함.
 // 인덱스와 함께 파일의 현재 크기를 저장하는데, 인덱스 항목을 추가하며 인덱스 파일의 데이터 양을 추적하기 위함
 // 인덱스 파일은 최대 인덱스 크기로 바꾼 다음 메모리 맵 파일을 만들어주며, 생성한 인덱스를 리턴
+// 새로 만드는 파일이면 맨 앞에 버전 바이트를 박아두고, 기존 파일이면 그 버전 바이트를
+// 검증해 구버전(버전 바이트가 없던 12바이트-엔트리) 인덱스를 이 포맷으로 잘못 읽지 않게 한다.
 func newIndex(f *os.File, c Config) (*index, error) {
 	idx := &index{
 		file: f,
@@ -36,9 +58,26 @@ func newIndex(f *os.File, c Config) (*index, error) {
 	if err != nil {
 		return nil, err
 	}
-	idx.size = uint64(fi.Size()) // 현재 사이즈 저장
+	if fi.Size() == 0 {
+		idx.dataStart = idxVersionWidth
+		if _, err := f.Write([]byte{idxVersion}); err != nil {
+			return nil, err
+		}
+	} else {
+		versionByte := make([]byte, idxVersionWidth)
+		if _, err := f.ReadAt(versionByte, 0); err != nil {
+			return nil, fmt.Errorf("index: 버전 바이트를 읽을 수 없음: %w", err)
+		}
+		if versionByte[0] != idxVersion {
+			return nil, fmt.Errorf(
+				"index: 지원하지 않는 인덱스 파일 버전 %d - 버전 바이트 도입 이전의 인덱스는 "+
+					"이 포맷과 호환되지 않으므로 세그먼트를 다시 만들어야 함", versionByte[0])
+		}
+		idx.dataStart = idxVersionWidth
+		idx.size = uint64(fi.Size()) - idx.dataStart // 현재 사이즈 저장
+	}
 	// 일단 최대 사이즈로 Truncate() 해줘서 mmap 대비
-	if err = os.Truncate(f.Name(), int64(c.Segment.MaxIndexBytes)); err != nil {
+	if err = os.Truncate(f.Name(), int64(idx.dataStart+c.Segment.MaxIndexBytes)); err != nil {
 		return nil, err
 	}
 	if idx.mmap, err = gommap.Map(idx.file.Fd(),
@@ -48,19 +87,24 @@ func newIndex(f *os.File, c Config) (*index, error) {
 	return idx, nil
 }
 
-// 메모리 맵 파일과 실제 파일의 데이터가 확실히 동기화되며,
-// 실제 파일 콘텐츠가 안정적인 저장소에 플러시됨. 이 후 실제 데이터가 있는 만큼만 잘라내고(truncate) 파일을 닫음.
-func (i *index) Close() error {
-	// 메모리 맵 파일부터 싱크
+// Sync는 메모리 맵 파일(mmap)을 msync하고 그 다음 파일을 fsync하여, 인덱스에
+// 기록된 항목들을 안정적인 저장소에 반영한다. 찢어진 mmap 쓰기(torn write)가
+// 디스크에 반쯤만 반영된 채 남지 않도록, 쓰기 경로에서 명시적으로 호출할 수 있다.
+func (i *index) Sync() error {
 	if err := i.mmap.Sync(gommap.MS_SYNC); err != nil {
 		return err
 	}
-	// 그 다음 파일 싱크
-	if err := i.file.Sync(); err != nil {
+	return i.file.Sync()
+}
+
+// 메모리 맵 파일과 실제 파일의 데이터가 확실히 동기화되며,
+// 실제 파일 콘텐츠가 안정적인 저장소에 플러시됨. 이 후 실제 데이터가 있는 만큼만 잘라내고(truncate) 파일을 닫음.
+func (i *index) Close() error {
+	if err := i.Sync(); err != nil {
 		return err
 	}
-	// 이제 실제 크기만큼 다시 자르기
-	if err := i.file.Truncate(int64(i.size)); err != nil {
+	// 이제 버전 헤더 + 실제 크기만큼 다시 자르기
+	if err := i.file.Truncate(int64(i.dataStart + i.size)); err != nil {
 		return err
 	}
 	// => 에러 메시지에서 나타난 syscall.Errno 1224는 파일이 현재 다른 프로세스에 의해 사용 중이라는 의미
@@ -71,10 +115,12 @@ func (i *index) Close() error {
 	return i.file.Close()
 }
 
-// in 번째 인덱스를 읽어, 앞에 4바이트는 out, 그 다음 8바이트는 pos 정보로 파싱하여 리턴
-func (i *index) Read(off int64) (out uint32, pos uint64, err error) {
+// in 번째 인덱스를 읽어, 앞 4바이트는 out, 다음 4바이트는 fileNo, 그 다음 8바이트는
+// 파일 내 위치, 마지막 4바이트는 그 세 필드를 덮는 crc32c로 파싱하여 filePos에 담아
+// 리턴한다. crc32c가 맞지 않으면 mmap에 반쯤만 반영된 찢어진 쓰기로 보고 에러를 낸다.
+func (i *index) Read(off int64) (out uint32, pos filePos, err error) {
 	if i.size == 0 {
-		return 0, 0, io.EOF
+		return 0, filePos{}, io.EOF
 	}
 
 	if off == -1 {
@@ -83,27 +129,39 @@ func (i *index) Read(off int64) (out uint32, pos uint64, err error) {
 		out = uint32(off)
 	}
 
-	startingPos := uint64(out) * entWidth
-	if i.size < startingPos+entWidth {
-		return 0, 0, io.EOF
+	startingPos := i.dataStart + uint64(out)*entWidth
+	if i.dataStart+i.size < startingPos+entWidth {
+		return 0, filePos{}, io.EOF
+	}
+
+	body := i.mmap[startingPos : startingPos+entBodyWidth]
+	wantCRC := enc.Uint32(i.mmap[startingPos+entBodyWidth : startingPos+entWidth])
+	if got := crc32.Checksum(body, crcTable); got != wantCRC {
+		return 0, filePos{}, fmt.Errorf("index: crc 불일치 (entry %d): 찢어진 mmap 쓰기로 추정됨", out)
 	}
 
-	out = enc.Uint32(i.mmap[startingPos : startingPos+offWidth])
-	pos = enc.Uint64(i.mmap[startingPos+offWidth : startingPos+entWidth])
+	out = enc.Uint32(body[:offWidth])
+	fileNo := enc.Uint32(body[offWidth : offWidth+fileNoWidth])
+	p := enc.Uint64(body[offWidth+fileNoWidth:])
 
-	return out, pos, nil
+	return out, filePos{FileNo: fileNo, Offset: p}, nil
 }
 
-// 오프셋과 위치를 매개변수로 받아 인덱스를 추가
+// 오프셋과 위치(fileNo, pos)를 매개변수로 받아 인덱스를 추가
 // 추가할 공간을 먼저 확인하고, 공간이 있다면 인코딩한 다음 메모리 맵 파일에 쓴다.
+// 세 필드를 덮는 crc32c를 마지막에 덧붙여, 나중에 Read가 찢어진 쓰기를 잡아낼 수 있게 한다.
 // 마지막으로 size를 증가시켜 다음에 쓸 위치를 가리키게 함.
-func (i *index) Write(off uint32, pos uint64) error {
+func (i *index) Write(off uint32, pos filePos) error {
 	if i.IsMaxed() {
 		return io.EOF
 	}
 
-	enc.PutUint32(i.mmap[i.size:i.size+offWidth], off)
-	enc.PutUint64(i.mmap[i.size+offWidth:i.size+entWidth], pos)
+	start := i.dataStart + i.size
+	enc.PutUint32(i.mmap[start:start+offWidth], off)
+	enc.PutUint32(i.mmap[start+offWidth:start+offWidth+fileNoWidth], pos.FileNo)
+	enc.PutUint64(i.mmap[start+offWidth+fileNoWidth:start+entBodyWidth], pos.Offset)
+	crc := crc32.Checksum(i.mmap[start:start+entBodyWidth], crcTable)
+	enc.PutUint32(i.mmap[start+entBodyWidth:start+entWidth], crc)
 	i.size += uint64(entWidth)
 	return nil
 }
@@ -113,5 +171,5 @@ func (i *index) Name() string {
 }
 
 func (i *index) IsMaxed() bool {
-	return uint64(len(i.mmap)) < i.size+entWidth
+	return uint64(len(i.mmap)) < i.dataStart+i.size+entWidth
 }
@@ -0,0 +1,49 @@
+package log
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestIndexWriteReadRoundTrip은 Write로 기록한 항목을 Read로 그대로 되읽을 수
+// 있는지 검증한다.
+func TestIndexWriteReadRoundTrip(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "index_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	var c Config
+	c.Segment.MaxIndexBytes = 1024
+	idx, err := newIndex(f, c)
+	require.NoError(t, err)
+	defer idx.Close()
+
+	want := filePos{FileNo: 3, Offset: 123}
+	require.NoError(t, idx.Write(0, want))
+
+	_, got, err := idx.Read(-1)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+// TestIndexRejectsUnversionedFile은 버전 바이트가 없는(이 포맷 도입 이전의) 인덱스
+// 파일을 열려고 하면 garbage 항목으로 잘못 해석하는 대신 명확한 에러를 내는지
+// 검증한다.
+func TestIndexRejectsUnversionedFile(t *testing.T) {
+	dir := t.TempDir()
+	name := path.Join(dir, "0.index")
+	// 버전 바이트 없이 12바이트짜리 레거시 항목 하나를 직접 써넣는다.
+	require.NoError(t, os.WriteFile(name, make([]byte, 12), 0644))
+
+	f, err := os.OpenFile(name, os.O_RDWR, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var c Config
+	c.Segment.MaxIndexBytes = 1024
+	_, err = newIndex(f, c)
+	require.Error(t, err)
+}
@@ -0,0 +1,284 @@
+package log
+
+import (
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	api "github.com/JINs-software/GoLogDB/api/v1"
+)
+
+// Log는 baseOffset 순으로 정렬된 세그먼트들의 목록을 관리한다.
+// 활성 세그먼트가 가득 차면 그 다음 오프셋을 baseOffset으로 갖는 새 세그먼트를 열고,
+// 이전 세그먼트들은 읽기 전용으로 남는다.
+type Log struct {
+	mu sync.RWMutex
+
+	Dir    string
+	Config Config
+
+	activeSegment *segment
+	segments      []*segment
+}
+
+// NewLog는 dir 아래에 이미 존재하는 세그먼트 파일들로부터 Log를 복원한다.
+// dir이 비어있다면 Config.Segment.InitialOffset을 baseOffset으로 하는 첫 세그먼트를 만든다.
+func NewLog(dir string, c Config) (*Log, error) {
+	if c.Segment.MaxStoreBytes == 0 {
+		c.Segment.MaxStoreBytes = 1024
+	}
+	if c.Segment.MaxIndexBytes == 0 {
+		c.Segment.MaxIndexBytes = 1024
+	}
+	l := &Log{
+		Dir:    dir,
+		Config: c,
+	}
+	return l, l.setup()
+}
+
+// setup은 dir에 남아있는 세그먼트들을 baseOffset 오름차순으로 읽어 목록을 재구성한다.
+// store는 chunk0-3부터 세그먼트당 파일 하나가 아니라 "{baseOffset}-{fileNo}.store"로
+// 여러 개 있을 수 있으므로, 세그먼트당 정확히 하나씩만 존재하는 .index 파일의 이름에서
+// baseOffset을 읽는다. (.store 파일 이름으로 세그먼트를 세면 파일이 여러 개로 롤오버된
+// 세그먼트가 중복으로 잡히고, "{base}-{fileNo}" 전체를 숫자로 파싱하려다 실패해 base가
+// 0으로 뭉개지는 문제가 있었다.)
+func (l *Log) setup() error {
+	files, err := os.ReadDir(l.Dir)
+	if err != nil {
+		return err
+	}
+	var baseOffsets []uint64
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, ".index") {
+			continue
+		}
+		offStr := strings.TrimSuffix(name, ".index")
+		off, err := strconv.ParseUint(offStr, 10, 0)
+		if err != nil {
+			continue
+		}
+		baseOffsets = append(baseOffsets, off)
+	}
+	sort.Slice(baseOffsets, func(i, j int) bool {
+		return baseOffsets[i] < baseOffsets[j]
+	})
+	for _, off := range baseOffsets {
+		if err = l.newSegment(off); err != nil {
+			return err
+		}
+	}
+	if l.segments == nil {
+		if err = l.newSegment(l.Config.Segment.InitialOffset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Log) newSegment(off uint64) error {
+	s, err := newSegment(l.Dir, off, l.Config)
+	if err != nil {
+		return err
+	}
+	l.segments = append(l.segments, s)
+	l.activeSegment = s
+	return nil
+}
+
+// Append는 활성 세그먼트에 레코드를 쓰고, 쓰고 난 뒤 활성 세그먼트가 가득 찼다면
+// 다음 레코드를 받을 새 세그먼트를 미리 준비해둔다.
+func (l *Log) Append(record *api.Record) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	off, err := l.activeSegment.Append(record)
+	if err != nil {
+		return 0, err
+	}
+	if l.activeSegment.IsMaxed() {
+		err = l.newSegment(off + 1)
+	}
+	return off, err
+}
+
+// AppendBatch는 records를 활성 세그먼트에 한 번에 Append해, 레코드마다 따로
+// write + fsync가 일어나는 것을 막는다. 기존 Append처럼 다 쓰고 난 뒤 활성
+// 세그먼트가 가득 찼다면 다음 세그먼트를 미리 준비해두지만, 이 배치 자체가
+// 세그먼트 경계를 걸치는 경우는 다루지 않는다 - segment.IsMaxed는 세그먼트가
+// 가득 차기 전에 호출 측(ProduceBatch 등)이 배치 크기를 적절히 나누는 것을 전제로 한다.
+func (l *Log) AppendBatch(records []*api.Record) ([]uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	offsets, err := l.activeSegment.AppendBatch(records)
+	if err != nil {
+		return nil, err
+	}
+	if l.activeSegment.IsMaxed() {
+		err = l.newSegment(offsets[len(offsets)-1] + 1)
+	}
+	return offsets, err
+}
+
+// Flush는 활성 세그먼트의 store/index를 즉시 fsync/msync한다.
+func (l *Log) Flush() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.activeSegment.Flush()
+}
+
+// segmentFor는 off를 포함하는 세그먼트를 찾는다. l.mu가 잠긴 채로 호출돼야 한다.
+func (l *Log) segmentFor(off uint64) *segment {
+	for _, seg := range l.segments {
+		if seg.baseOffset <= off && off < seg.nextOffset {
+			return seg
+		}
+	}
+	return nil
+}
+
+// Read는 off를 포함하는 세그먼트를 찾아 그 세그먼트에 위임한다.
+func (l *Log) Read(off uint64) (*api.Record, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	s := l.segmentFor(off)
+	if s == nil {
+		return nil, api.ErrOffsetOutOfRange{Offset: off}
+	}
+	return s.Read(off)
+}
+
+// ReadRaw는 off에 해당하는 레코드의 페이로드를 codec 역직렬화 없이 그대로 리턴한다.
+// 리턴된 released 함수는 반드시 호출해 내부 버퍼 풀에 반납해야 한다(segment.ReadRaw 참고).
+func (l *Log) ReadRaw(off uint64) ([]byte, func(), error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	s := l.segmentFor(off)
+	if s == nil {
+		return nil, nil, api.ErrOffsetOutOfRange{Offset: off}
+	}
+	return s.ReadRaw(off)
+}
+
+// Scrub은 모든 세그먼트를 순서대로 돌며 손상된 레코드가 있는지 검사한다. 세그먼트
+// 하나하나는 독립적으로 오래 걸릴 수 있으므로, 매 세그먼트 검사 전에 ctx가 취소됐는지
+// 확인해 백그라운드 스크럽을 중간에 멈출 수 있게 한다. 개별 세그먼트의 Verify가
+// 에러를 내더라도(예: I/O 실패) 나머지 세그먼트는 계속 검사하고, 마지막 에러를 리턴한다.
+func (l *Log) Scrub(ctx context.Context) (badOffsets []uint64, err error) {
+	l.mu.RLock()
+	segments := make([]*segment, len(l.segments))
+	copy(segments, l.segments)
+	l.mu.RUnlock()
+
+	for _, seg := range segments {
+		select {
+		case <-ctx.Done():
+			return badOffsets, ctx.Err()
+		default:
+		}
+		bad, verr := seg.Verify()
+		if verr != nil {
+			err = verr
+		}
+		badOffsets = append(badOffsets, bad...)
+	}
+	return badOffsets, err
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, seg := range l.segments {
+		if err := seg.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Log) Remove() error {
+	if err := l.Close(); err != nil {
+		return err
+	}
+	return os.RemoveAll(l.Dir)
+}
+
+// Reset은 로그 디렉터리를 지우고 빈 상태로 다시 연다. Raft의 Restore처럼
+// 스냅샷으로부터 로그 전체를 새로 채워야 할 때 사용한다.
+func (l *Log) Reset() error {
+	if err := l.Remove(); err != nil {
+		return err
+	}
+	// Remove는 디렉터리 자체까지 지우므로 setup이 읽을 수 있게 다시 만든다.
+	if err := os.MkdirAll(l.Dir, 0755); err != nil {
+		return err
+	}
+	l.segments = nil
+	l.activeSegment = nil
+	return l.setup()
+}
+
+func (l *Log) LowestOffset() (uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.segments[0].baseOffset, nil
+}
+
+func (l *Log) HighestOffset() (uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	off := l.segments[len(l.segments)-1].nextOffset
+	if off == 0 {
+		return 0, nil
+	}
+	return off - 1, nil
+}
+
+// Truncate는 lowest 이하의 오프셋만 담고 있는 오래된 세그먼트를 지운다.
+// 스냅샷 이후 더는 필요 없어진 로그 구간을 정리할 때 쓴다.
+func (l *Log) Truncate(lowest uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var kept []*segment
+	for _, s := range l.segments {
+		if s.nextOffset <= lowest+1 {
+			if err := s.Remove(); err != nil {
+				return err
+			}
+			continue
+		}
+		kept = append(kept, s)
+	}
+	l.segments = kept
+	return nil
+}
+
+// Reader는 모든 세그먼트의 store 파일을 baseOffset 순서로 이어 붙여 읽는 io.Reader를 리턴한다.
+// Raft FSM이 스냅샷을 찍을 때 로그 전체를 바이트 스트림으로 복제하는 용도로 쓰인다.
+func (l *Log) Reader() io.Reader {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	readers := make([]io.Reader, len(l.segments))
+	for i, seg := range l.segments {
+		// store.ReadAt은 fileNo별 코덱 헤더를 이미 건너뛰고 레코드 프레이밍만 노출한다.
+		readers[i] = &originReader{seg.store, 0}
+	}
+	return io.MultiReader(readers...)
+}
+
+// originReader는 store의 쓰기 버퍼를 거치지 않고 파일을 그대로 읽어,
+// store.Append가 기록한 [길이][crc32c][페이로드] 프레이밍을 그대로 노출한다.
+type originReader struct {
+	*store
+	off int64
+}
+
+func (o *originReader) Read(p []byte) (int, error) {
+	n, err := o.ReadAt(p, o.off)
+	o.off += int64(n)
+	return n, err
+}
@@ -0,0 +1,49 @@
+package log
+
+import (
+	"testing"
+
+	api "github.com/JINs-software/GoLogDB/api/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLogRestartRecoversSegments는 세그먼트가 두 번째 store 파일로 롤오버할 만큼
+// 레코드를 채운 뒤 Log를 닫고 다시 열어, setup이 .index 파일만으로 세그먼트들을
+// (baseOffset이 0이 아닌 경우를 포함해) 정확히 복원하는지 검증한다. store 파일 이름이
+// "{baseOffset}-{fileNo}.store"가 된 이후 setup이 이를 baseOffset으로 잘못 파싱하면
+// 재시작 후 레코드가 유실되거나 중복 세그먼트가 생긴다.
+func TestLogRestartRecoversSegments(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 64
+	c.Segment.MaxFileBytes = 32 // 세그먼트 하나가 여러 store 파일로 롤오버되게 함
+
+	l, err := NewLog(dir, c)
+	require.NoError(t, err)
+
+	const n = 10
+	for i := 0; i < n; i++ {
+		_, err := l.Append(&api.Record{Value: []byte("0123456789")})
+		require.NoError(t, err)
+	}
+	require.True(t, len(l.segments) > 1, "test 전제가 깨짐: 세그먼트가 하나도 안 나뉨")
+	wantSegments := len(l.segments)
+	highest, err := l.HighestOffset()
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	reopened, err := NewLog(dir, c)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	require.Equal(t, wantSegments, len(reopened.segments))
+	reopenedHighest, err := reopened.HighestOffset()
+	require.NoError(t, err)
+	require.Equal(t, highest, reopenedHighest)
+
+	for off := uint64(0); off < n; off++ {
+		record, err := reopened.Read(off)
+		require.NoError(t, err)
+		require.Equal(t, []byte("0123456789"), record.Value)
+	}
+}
@@ -4,11 +4,18 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sync"
 
 	api "github.com/JINs-software/GoLogDB/api/v1"
-	"google.golang.org/protobuf/proto"
 )
 
+// rawBufPool은 segment.ReadRaw가 레코드를 읽을 때마다 새 []byte를 할당하지 않도록
+// 버퍼를 재사용하는 풀. api.Record로 역직렬화하지 않고 페이로드를 그대로 넘겨야 하는
+// 팬아웃이 큰 컨슈머 경로(zero-copy consume)에서 쓰인다.
+var rawBufPool = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
 // 세그먼트는 store와 index를 감싸고, 둘 사이의 작업을 조율
 // ex) 로그가 활성 세그먼트에 레코드를 추가할 때,
 // 		세그먼트는 데이터를 store에 쓰고 새로운 인덱스 항목을 index에 추가함.
@@ -22,6 +29,7 @@ type segment struct {
 	index                  *index
 	baseOffset, nextOffset uint64
 	config                 Config
+	codec                  Codec
 }
 
 // 활성 세그먼트가 가득 찰 때, 로그에 새로운 세그먼트를 생성할 시 newSegment를 호출
@@ -33,20 +41,17 @@ type segment struct {
 // 인덱스가 비어있다면 다음 레코드는 세그먼트의 첫 레코드, 오프셋은 세그먼트의 베이스 오프셋이 됨
 // 인덱스에 하나 이상의 레코드가 있다면, 다음 레코드의 오프셋은 레코드의 마지막 오프셋이 됨 (베이스 오프셋과 상대 오프셋 + 1)
 func newSegment(dir string, baseOffset uint64, c Config) (*segment, error) {
+	codec := c.Codec
+	if codec == nil {
+		codec = ProtoCodec{}
+	}
 	s := &segment{
 		baseOffset: baseOffset,
 		config:     c,
+		codec:      codec,
 	}
 	var err error
-	storeFile, err := os.OpenFile(
-		path.Join(dir, fmt.Sprintf("%d%s", baseOffset, ".store")),
-		os.O_RDWR|os.O_CREATE|os.O_APPEND,
-		0644,
-	)
-	if err != nil {
-		return nil, err
-	}
-	if s.store, err = newStore(storeFile); err != nil {
+	if s.store, err = newStore(dir, baseOffset, codec.Name(), c); err != nil {
 		return nil, err
 	}
 	indexFile, err := os.OpenFile(
@@ -71,7 +76,7 @@ func newSegment(dir string, baseOffset uint64, c Config) (*segment, error) {
 func (s *segment) Append(record *api.Record) (offset uint64, err error) {
 	cur := s.nextOffset
 	record.Offset = cur
-	p, err := proto.Marshal(record)
+	p, err := s.codec.Marshal(record)
 	if err != nil {
 		return 0, err
 	}
@@ -90,6 +95,35 @@ func (s *segment) Append(record *api.Record) (offset uint64, err error) {
 	return cur, nil
 }
 
+// AppendBatch는 여러 레코드를 한 번의 store.AppendBatch 호출로 써서, 레코드마다
+// write + fsync가 따로 일어나지 않게 한다. 레코드들은 현재 활성 세그먼트에만 쓰이며,
+// 이 호출 하나가 세그먼트 경계를 넘어가는 경우는 다루지 않는다(Log.AppendBatch 참고).
+func (s *segment) AppendBatch(records []*api.Record) ([]uint64, error) {
+	payloads := make([][]byte, len(records))
+	for i, record := range records {
+		record.Offset = s.nextOffset + uint64(i)
+		p, err := s.codec.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		payloads[i] = p
+	}
+	_, positions, err := s.store.AppendBatch(payloads)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make([]uint64, len(positions))
+	for i, pos := range positions {
+		off := s.nextOffset
+		if err := s.index.Write(uint32(off-s.baseOffset), pos); err != nil {
+			return nil, err
+		}
+		offsets[i] = off
+		s.nextOffset++
+	}
+	return offsets, nil
+}
+
 func (s *segment) Read(off uint64) (*api.Record, error) {
 	_, pos, err := s.index.Read(int64(off - s.baseOffset))
 	if err != nil {
@@ -100,17 +134,72 @@ func (s *segment) Read(off uint64) (*api.Record, error) {
 		return nil, err
 	}
 	record := &api.Record{}
-	err = proto.Unmarshal(p, record)
+	err = s.codec.Unmarshal(p, record)
 	return record, err
 }
 
+// ReadRaw는 off에 해당하는 레코드의 페이로드를 codec으로 역직렬화하지 않고 그대로
+// 리턴한다. 리턴된 슬라이스는 rawBufPool에서 빌려온 버퍼를 가리키므로, 다 쓰고 나면
+// 반드시 released()를 호출해 풀에 반납해야 한다(그렇지 않으면 메모리가 새는 건 아니지만
+// 풀의 재사용 효과가 사라진다).
+func (s *segment) ReadRaw(off uint64) (payload []byte, released func(), err error) {
+	_, pos, err := s.index.Read(int64(off - s.baseOffset))
+	if err != nil {
+		return nil, nil, err
+	}
+	size, err := s.store.RecordSize(pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	bufPtr := rawBufPool.Get().(*[]byte)
+	buf := *bufPtr
+	if uint64(cap(buf)) < size {
+		buf = make([]byte, size)
+	} else {
+		buf = buf[:size]
+	}
+	if _, err := s.store.ReadInto(pos, buf); err != nil {
+		rawBufPool.Put(bufPtr)
+		return nil, nil, err
+	}
+	*bufPtr = buf
+	return buf, func() { rawBufPool.Put(bufPtr) }, nil
+}
+
+// Verify는 이 세그먼트의 모든 인덱스 항목을 순회하며 대응하는 store 레코드의
+// crc32c를 검증해, bit-rot이나 찢어진 쓰기로 손상된 레코드의 오프셋 목록을 리턴한다.
+// 인덱스 항목 자체가 찢어져 읽을 수 없는 경우도 해당 절대 오프셋을 손상으로 기록한다.
+func (s *segment) Verify() (badOffsets []uint64, err error) {
+	n := s.index.size / entWidth
+	for i := uint64(0); i < n; i++ {
+		relOff, pos, err := s.index.Read(int64(i))
+		if err != nil {
+			badOffsets = append(badOffsets, s.baseOffset+i)
+			continue
+		}
+		if _, err := s.store.Read(pos); err != nil {
+			badOffsets = append(badOffsets, s.baseOffset+uint64(relOff))
+		}
+	}
+	return badOffsets, nil
+}
+
 // [segement.IsMaxed]
 // 세그먼트의 store 또는 index가 최대 크기에 도달했는지를 리턴
 func (s *segment) IsMaxed() bool {
-	return s.store.size >= s.config.Segment.MaxStoreBytes ||
+	return s.store.Size() >= s.config.Segment.MaxStoreBytes ||
 		s.index.size+entWidth > s.config.Segment.MaxIndexBytes
 }
 
+// Flush는 Sync 모드와 무관하게 store의 쓰기 버퍼를 fsync하고 index의 메모리
+// 맵 파일을 msync하여, 지금까지 쓰인 내용을 즉시 안정적인 저장소에 반영한다.
+func (s *segment) Flush() error {
+	if err := s.store.Flush(); err != nil {
+		return err
+	}
+	return s.index.Sync()
+}
+
 func (s *segment) Close() error {
 	if err := s.index.Close(); err != nil {
 		return err
@@ -122,16 +211,13 @@ func (s *segment) Close() error {
 }
 
 // [segement.Remove]
-// 세그먼트를 닫고 인덱스 파일과 저장 파일 삭제
+// 세그먼트를 닫고 인덱스 파일과 store의 물리 파일들을 모두 삭제
 func (s *segment) Remove() error {
-	if err := s.Close(); err != nil {
+	if err := s.index.Close(); err != nil {
 		return err
 	}
 	if err := os.Remove(s.index.Name()); err != nil {
 		return err
 	}
-	if err := os.Remove(s.store.Name()); err != nil {
-		return err
-	}
-	return nil
+	return s.store.RemoveAll()
 }
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"os"
+	"testing"
+
+	api "github.com/JINs-software/GoLogDB/api/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSegmentReadRaw는 ReadRaw가 codec으로 역직렬화하지 않고 Append 때 쓴 것과
+// 동일한 바이트를 그대로 리턴하는지, 그리고 released 콜백으로 버퍼를 풀에 반납한 뒤
+// 다시 ReadRaw해도 같은 바이트를 얻는지 검증한다.
+func TestSegmentReadRaw(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 1024
+	c.Segment.MaxIndexBytes = 1024
+
+	s, err := newSegment(dir, 0, c)
+	require.NoError(t, err)
+	defer s.Close()
+
+	record := &api.Record{Value: []byte("zero-copy payload")}
+	off, err := s.Append(record)
+	require.NoError(t, err)
+
+	// Append가 record.Offset을 제자리에서 채우므로, 그 값을 그대로 재직렬화하면
+	// store에 실제로 쓰인 바이트와 같아야 한다.
+	want, err := (ProtoCodec{}).Marshal(record)
+	require.NoError(t, err)
+
+	payload, released, err := s.ReadRaw(off)
+	require.NoError(t, err)
+	require.Equal(t, want, payload)
+	released()
+
+	// 풀에서 새 버퍼를 받아도 다시 같은 레코드를 정확히 읽을 수 있어야 한다.
+	payload2, released2, err := s.ReadRaw(off)
+	require.NoError(t, err)
+	require.Equal(t, want, payload2)
+	released2()
+}
+
+// TestSegmentVerifyFindsCorruption은 store 레코드 하나가 변조됐을 때 Verify가
+// 그 레코드의 절대 오프셋을 badOffsets에 담아 리턴하고, 나머지 멀쩡한 레코드는
+// 보고하지 않는지 검증한다.
+func TestSegmentVerifyFindsCorruption(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 1024
+	c.Segment.MaxIndexBytes = 1024
+
+	s, err := newSegment(dir, 10, c)
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := s.Append(&api.Record{Value: []byte("fine")})
+		require.NoError(t, err)
+	}
+
+	_, pos, err := s.index.Read(1) // 베이스 오프셋 기준 상대 offset 1 -> 절대 오프셋 11
+	require.NoError(t, err)
+	require.NoError(t, s.store.Flush())
+	sf, err := s.store.fileByNo(pos.FileNo)
+	require.NoError(t, err)
+	// store는 파일을 O_APPEND로 열어 WriteAt을 쓸 수 없으므로 별도 핸들로 변조한다.
+	raw, err := os.OpenFile(sf.file.Name(), os.O_RDWR, 0644)
+	require.NoError(t, err)
+	defer raw.Close()
+	_, err = raw.WriteAt([]byte("XXXX"), sf.dataStart+int64(pos.Offset)+lenWidth+crcWidth)
+	require.NoError(t, err)
+
+	bad, err := s.Verify()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{11}, bad)
+}
@@ -2,101 +2,614 @@ package log
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
 	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // store에서 여러 번 참조하는 변수
 var (
-	enc = binary.BigEndian // 레코드의 크기와 인덱스 항목을 저장할 때의 인코딩 정의
+	enc      = binary.BigEndian               // 레코드의 크기와 인덱스 항목을 저장할 때의 인코딩 정의
+	crcTable = crc32.MakeTable(crc32.Castagnoli) // 레코드/인덱스 항목 체크섬에 쓰는 CRC-32C 테이블
 )
 
 const (
-	lenWidth = 8 // 레코드의 길이를 저장하는 바이트 개수 정의
+	lenWidth       = 8  // 레코드의 길이를 저장하는 바이트 개수 정의
+	crcWidth       = 4  // 레코드 페이로드의 CRC32C를 저장하는 바이트 개수 정의
+	codecNameWidth = 16 // store 파일 0의 코덱 이름 헤더 폭
+	versionWidth   = 1  // store 파일 0의 맨 앞에 박아두는 포맷 버전 바이트의 폭
+
+	// storeVersion은 현재 store 파일 포맷의 버전. 레코드 프레이밍에 CRC32C가
+	// 추가되며 1에서 2로 올랐다 - 버전 바이트가 없는(0x20 미만이 아닌 값으로
+	// 시작하는) 파일은 버전 바이트 도입 이전의 레거시 포맷으로 간주한다.
+	storeVersion byte = 2
+
+	// defaultSyncIntervalMs는 SyncInterval 모드인데 IntervalMs가 설정되지 않은 경우
+	// 쓰이는 기본 커밋 주기. ticker가 없으면 MaxBatchBytes를 채우지 못하는 부분
+	// 배치(또는 그 예산 자체가 없는 경우)는 commit()을 트리거할 조건이 영영 오지
+	// 않아 영원히 블록되므로, MaxBatchBytes 설정 여부와 무관하게 최소한의 주기적
+	// 커밋을 보장한다.
+	defaultSyncIntervalMs = 10
 )
 
+// SyncMode는 store가 언제 fsync를 호출해 쓰기를 안정적인 저장소에 반영할지를 결정한다.
+type SyncMode int
+
+const (
+	SyncNone     SyncMode = iota // fsync를 호출하지 않음. Read나 Close 시점에만 버퍼가 flush됨
+	SyncInterval                 // IntervalMs 주기 또는 MaxBatchBytes 누적마다 모아서 fsync
+	SyncAlways                   // 레코드 하나가 쓰일 때마다 즉시 fsync
+)
+
+// appendRequest/appendResult는 group-commit 고루틴에 단건 Append를 위임하고
+// 그 결과를 돌려받기 위한 내부 메시지.
+type appendRequest struct {
+	p    []byte
+	resC chan appendResult
+}
+
+type appendResult struct {
+	n   uint64
+	pos filePos
+	err error
+}
+
+// filePos는 멀티 파일 store 안에서 레코드 하나의 절대 위치를 가리킨다.
+// 파일 번호(FileNo)와 그 파일 안에서의 레코드 데이터 기준 오프셋(Offset)으로 구성된다.
+type filePos struct {
+	FileNo uint32
+	Offset uint64
+}
+
+// storeFile은 store를 이루는 물리 파일 하나를 감싼다. dataStart는 레코드 프레이밍이
+// 시작되는 파일 내 오프셋으로, 헤더를 담는 파일 0만 versionWidth+codecNameWidth만큼
+// (버전 바이트가 없는 레거시 파일이면 codecNameWidth만큼) 밀려 있다.
+type storeFile struct {
+	fileNo    uint32
+	file      *os.File
+	size      uint64 // 이 파일에 쓰인 레코드 데이터 바이트 수 (헤더 제외)
+	dataStart int64
+}
+
 // [store 구조체]
-// 파일의 단순한 래퍼(wrapper), 파일에 바이트 값들을 추가하거나 읽는 두 개의 메서드를 가짐.
+// 하나의 논리적 세그먼트 저장소를 여러 개의 물리 파일로 쪼개 보관한다(빅파일/롤링 스토어).
+// 활성 파일이 MaxFileBytes에 도달하면 다음 번호의 새 파일로 롤오버하므로, 세그먼트 하나가
+// 단일 파일의 크기 제한을 넘어설 수 있고, 오래된 파일만 따로 콜드 스토리지로 옮길 수도 있다.
 type store struct {
-	*os.File
-	mu   sync.Mutex
-	buf  *bufio.Writer
-	size uint64
+	dir          string
+	baseOffset   uint64
+	maxFileBytes uint64
+	codecName    string
+
+	syncMode      SyncMode
+	syncInterval  time.Duration
+	maxBatchBytes uint64
+	appendc       chan appendRequest
+	wg            sync.WaitGroup
+
+	mu    sync.Mutex
+	files []*storeFile // fileNo 오름차순. 마지막 원소가 쓰기 중인 활성 파일.
+	buf   *bufio.Writer
 }
 
-func newStore(f *os.File) (*store, error) {
-	// os.Stat()를 호출하여 파일 크기를 알아두었다가 데이터가 있는 파일로 스토어를 생성할 때 사용
-	// (ex, 서비스를 재시작할 때 필요)
-	fi, err := os.Stat(f.Name())
+// newStore는 dir 아래 baseOffset에 속한 store 파일(들)을 연다.
+// 이미 파일이 있다면 fileNo 오름차순으로 전부 열어 이어쓸 준비를 하고, 없다면 파일 0을 새로 만든다.
+// c.Sync는 동시에 들어오는 단건 Append들을 하나의 write + fsync로 묶어주는
+// group-commit 고루틴(runCommitter)의 동작 방식을 결정한다.
+func newStore(dir string, baseOffset uint64, codecName string, c Config) (*store, error) {
+	maxFileBytes := c.Segment.MaxFileBytes
+	if maxFileBytes == 0 {
+		maxFileBytes = 1 << 30 // 1GiB
+	}
+	intervalMs := c.Sync.IntervalMs
+	if c.Sync.Mode == SyncInterval && intervalMs <= 0 {
+		// IntervalMs가 없으면 ticker가 아예 없으므로, MaxBatchBytes가 설정돼 있어도
+		// 그 예산을 못 채우는 마지막(또는 유일한) Append는 commit()을 트리거할 조건이
+		// 영영 오지 않아 블록된다 - MaxBatchBytes 설정 여부와 무관하게 최소한의
+		// 주기적 커밋으로 대체해, 부분 배치도 항상 빠져나가게 한다.
+		intervalMs = defaultSyncIntervalMs
+	}
+	s := &store{
+		dir:           dir,
+		baseOffset:    baseOffset,
+		maxFileBytes:  maxFileBytes,
+		codecName:     codecName,
+		syncMode:      c.Sync.Mode,
+		syncInterval:  time.Duration(intervalMs) * time.Millisecond,
+		maxBatchBytes: c.Sync.MaxBatchBytes,
+		appendc:       make(chan appendRequest),
+	}
+	fileNos, err := s.existingFileNos()
 	if err != nil {
 		return nil, err
 	}
-	size := uint64(fi.Size())
-	return &store{
-		File: f,
-		size: size,
-		buf:  bufio.NewWriter(f),
-	}, nil
+	if len(fileNos) == 0 {
+		fileNos = []uint32{0}
+	}
+	for _, no := range fileNos {
+		if err := s.openFile(no); err != nil {
+			return nil, err
+		}
+	}
+	if c.Segment.Repair {
+		if err := s.repair(); err != nil {
+			return nil, err
+		}
+	}
+	s.buf = bufio.NewWriter(s.files[len(s.files)-1].file)
+	s.wg.Add(1)
+	go s.runCommitter()
+	return s, nil
 }
 
-// [store.Appednd()]
-// 바이트 슬라이스를 받아 저장 파일에 append
-// 나중에 읽을 때 얼마나 읽어야 할지 알 수 있도록 레코드 크기 또한 기록
-// 실제 쓴 바이트 수와 저장 파일의 어느 위치에 썼는지를 리턴 (세그먼트는 레코드의 인덱스 항목을 생성할 때 이 위치 정보를 활용)
-func (s *store) Append(p []byte) (n uint64, pos uint64, err error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	pos = s.size
+// runCommitter는 appendc로 들어오는 단건 Append 요청들을 모아 한 번의 write로
+// store에 쓰고, Config.Sync의 설정에 따라 그 묶음을 한 번만 fsync한다.
+// SyncInterval에서는 ticker가 울리거나 MaxBatchBytes를 채울 때까지 요청을 쌓아두므로,
+// 그 사이에 도착한 동시 Append들이 자연스럽게 하나의 write + fsync로 묶인다.
+func (s *store) runCommitter() {
+	defer s.wg.Done()
+
+	var tickc <-chan time.Time
+	if s.syncMode == SyncInterval && s.syncInterval > 0 {
+		ticker := time.NewTicker(s.syncInterval)
+		defer ticker.Stop()
+		tickc = ticker.C
+	}
+
+	var pending []appendRequest
+	var pendingBytes uint64
+	commit := func() {
+		if len(pending) == 0 {
+			return
+		}
+		s.mu.Lock()
+		for _, req := range pending {
+			n, pos, err := s.appendLocked(req.p)
+			req.resC <- appendResult{n: n, pos: pos, err: err}
+		}
+		if s.syncMode != SyncNone {
+			_ = s.syncLocked()
+		}
+		s.mu.Unlock()
+		pending = pending[:0]
+		pendingBytes = 0
+	}
+
+	for {
+		select {
+		case req, ok := <-s.appendc:
+			if !ok {
+				commit()
+				return
+			}
+			pending = append(pending, req)
+			pendingBytes += uint64(lenWidth + len(req.p))
+			switch {
+			case s.syncMode == SyncInterval:
+				if s.maxBatchBytes > 0 && pendingBytes >= s.maxBatchBytes {
+					commit()
+				}
+			default: // SyncNone, SyncAlways는 요청마다 즉시 커밋
+				commit()
+			}
+		case <-tickc:
+			commit()
+		}
+	}
+}
+
+func (s *store) storeFileName(no uint32) string {
+	return path.Join(s.dir, fmt.Sprintf("%d-%d.store", s.baseOffset, no))
+}
+
+// existingFileNos는 dir 안에서 이 세그먼트에 속한 store 파일들의 번호를 오름차순으로 찾는다.
+func (s *store) existingFileNos() ([]uint32, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("%d-", s.baseOffset)
+	var nos []uint32
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".store") {
+			continue
+		}
+		mid := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".store")
+		no, err := strconv.ParseUint(mid, 10, 32)
+		if err != nil {
+			continue
+		}
+		nos = append(nos, uint32(no))
+	}
+	sort.Slice(nos, func(i, j int) bool { return nos[i] < nos[j] })
+	return nos, nil
+}
+
+// openFile은 fileNo에 해당하는 물리 파일을 열고, 파일 0이면 버전 바이트와 코덱
+// 이름으로 된 헤더를 기록하거나(새 파일) 검증한다(기존 파일). 버전 바이트가 없는
+// (즉 첫 바이트가 0x20 미만의 예약값이 아닌) 파일은 CRC가 도입되기 전의 레거시
+// 포맷으로 보고 그에 맞는 헤더 폭으로 읽어, 예전 파일도 계속 열릴 수 있게 한다.
+func (s *store) openFile(no uint32) error {
+	f, err := os.OpenFile(s.storeFileName(no), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		return err
+	}
+
+	var dataStart int64
+	var dataSize uint64
+	if no == 0 {
+		if fi.Size() == 0 {
+			dataStart = versionWidth + codecNameWidth
+			header := make([]byte, dataStart)
+			header[0] = storeVersion
+			copy(header[versionWidth:], s.codecName)
+			if _, err := f.Write(header); err != nil {
+				return err
+			}
+		} else {
+			versionByte := make([]byte, versionWidth)
+			if _, err := f.ReadAt(versionByte, 0); err != nil {
+				return fmt.Errorf("store: 버전 바이트를 읽을 수 없음: %w", err)
+			}
+			nameOff := int64(0)
+			if versionByte[0] < 0x20 {
+				// 버전 바이트가 있는(현재) 포맷
+				if versionByte[0] != storeVersion {
+					return fmt.Errorf("store: 지원하지 않는 store 파일 버전 %d", versionByte[0])
+				}
+				dataStart = versionWidth + codecNameWidth
+				nameOff = versionWidth
+			} else {
+				// 버전 바이트 도입 이전(chunk0-2~4) 레거시 포맷: 코덱 이름이 파일 맨 앞부터 시작
+				dataStart = codecNameWidth
+			}
+			header := make([]byte, codecNameWidth)
+			if _, err := f.ReadAt(header, nameOff); err != nil {
+				return fmt.Errorf("store: codec 헤더를 읽을 수 없음: %w", err)
+			}
+			got := string(bytes.TrimRight(header, "\x00"))
+			if got != s.codecName {
+				return fmt.Errorf("store: 코덱 불일치 - 파일은 %q로 기록되었지만 설정은 %q", got, s.codecName)
+			}
+			dataSize = uint64(fi.Size()) - uint64(dataStart)
+		}
+	} else {
+		dataSize = uint64(fi.Size())
+	}
+
+	s.files = append(s.files, &storeFile{
+		fileNo:    no,
+		file:      f,
+		size:      dataSize,
+		dataStart: dataStart,
+	})
+	return nil
+}
+
+// repair는 이 store에 속한 모든 물리 파일을 파일 맨 앞부터 순서대로 훑으며
+// [길이][crc32c][페이로드] 프레이밍이 끝까지 온전한지 확인하고, 레코드가 잘려있거나
+// (쓰다 만 길이/페이로드) CRC가 맞지 않는 첫 레코드를 찾으면 그 직전 경계로 파일을
+// truncate한다. Config.Segment.Repair가 켜진 경우 newStore가 파일을 연 직후 호출돼,
+// 비정상 종료로 생긴 torn write를 시작 시점에 복구한다.
+func (s *store) repair() error {
+	for _, sf := range s.files {
+		good := uint64(0)
+		for good < sf.size {
+			header := make([]byte, lenWidth+crcWidth)
+			if _, err := sf.file.ReadAt(header, sf.dataStart+int64(good)); err != nil {
+				break
+			}
+			recLen := enc.Uint64(header[:lenWidth])
+			wantCRC := enc.Uint32(header[lenWidth:])
+			if good+uint64(lenWidth+crcWidth)+recLen > sf.size {
+				break
+			}
+			payload := make([]byte, recLen)
+			if _, err := sf.file.ReadAt(payload, sf.dataStart+int64(good)+lenWidth+crcWidth); err != nil {
+				break
+			}
+			if crc32.Checksum(payload, crcTable) != wantCRC {
+				break
+			}
+			good += uint64(lenWidth+crcWidth) + recLen
+		}
+		if good < sf.size {
+			if err := sf.file.Truncate(sf.dataStart + int64(good)); err != nil {
+				return err
+			}
+			sf.size = good
+		}
+	}
+	return nil
+}
+
+// rollover는 활성 파일을 플러시한 뒤 번호가 하나 큰 새 파일을 열어 쓰기 대상을 전환한다.
+func (s *store) rollover() error {
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	next := s.files[len(s.files)-1].fileNo + 1
+	if err := s.openFile(next); err != nil {
+		return err
+	}
+	s.buf = bufio.NewWriter(s.files[len(s.files)-1].file)
+	return nil
+}
+
+// [store.Append()]
+// p를 group-commit 고루틴(runCommitter)에 넘기고 그 결과를 기다린다. 동시에 들어오는
+// 여러 Append 호출이 하나의 write + fsync로 묶일 수 있도록, 실제 쓰기는 이 메서드가
+// 아니라 runCommitter가 수행한다.
+func (s *store) Append(p []byte) (n uint64, pos filePos, err error) {
+	resC := make(chan appendResult, 1)
+	s.appendc <- appendRequest{p: p, resC: resC}
+	res := <-resC
+	return res.n, res.pos, res.err
+}
+
+// appendLocked는 실제로 바이트를 활성 파일에 쓰는 부분으로, s.mu를 쥔 채로만
+// 호출돼야 한다(runCommitter, AppendBatch에서 사용). 추가하면 MaxFileBytes를
+// 넘기게 되고 활성 파일에 이미 레코드가 있다면, 먼저 새 파일로 롤오버한 뒤 쓴다.
+// 레코드는 [길이 uint64][crc32c uint32][페이로드]로 프레이밍되어, 나중에 읽을 때
+// 얼마나 읽어야 할지와 그 내용이 손상되지 않았는지를 함께 알 수 있다.
+// 실제 쓴 바이트 수와 저장 위치(fileNo, offset)를 리턴 (세그먼트는 인덱스 항목을 만들 때 이 위치를 활용)
+func (s *store) appendLocked(p []byte) (n uint64, pos filePos, err error) {
+	active := s.files[len(s.files)-1]
+	need := uint64(lenWidth + crcWidth + len(p))
+	if active.size > 0 && uint64(active.dataStart)+active.size+need > s.maxFileBytes {
+		if err = s.rollover(); err != nil {
+			return 0, filePos{}, err
+		}
+		active = s.files[len(s.files)-1]
+	}
+
+	pos = filePos{FileNo: active.fileNo, Offset: active.size}
 	if err := binary.Write(s.buf, enc, uint64(len(p))); err != nil {
-		return 0, 0, err
+		return 0, filePos{}, err
+	}
+	if err := binary.Write(s.buf, enc, crc32.Checksum(p, crcTable)); err != nil {
+		return 0, filePos{}, err
 	}
 	w, err := s.buf.Write(p)
 	if err != nil {
-		return 0, 0, err
+		return 0, filePos{}, err
 	}
-	w += lenWidth
-	s.size += uint64(w)
+	w += lenWidth + crcWidth
+	active.size += uint64(w)
 	return uint64(w), pos, nil
 }
 
+// AppendBatch는 여러 레코드를 한 번의 락 구간 안에서 순서대로 appendLocked하고,
+// 설정된 Sync 모드가 SyncNone이 아니라면 배치 전체에 대해 fsync를 한 번만 호출한다.
+// segment.AppendBatch가 여러 레코드를 한 번의 RPC/호출로 묶어 쓸 때 사용한다.
+func (s *store) AppendBatch(ps [][]byte) (ns []uint64, positions []filePos, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ns = make([]uint64, 0, len(ps))
+	positions = make([]filePos, 0, len(ps))
+	for _, p := range ps {
+		n, pos, err := s.appendLocked(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		ns = append(ns, n)
+		positions = append(positions, pos)
+	}
+	if s.syncMode != SyncNone {
+		if err := s.syncLocked(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return ns, positions, nil
+}
+
+// syncLocked는 쓰기 버퍼를 플러시하고 활성 파일을 fsync한다. s.mu를 쥔 채로만 호출돼야 한다.
+func (s *store) syncLocked() error {
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	active := s.files[len(s.files)-1]
+	return active.file.Sync()
+}
+
+// Flush는 Sync 모드와 무관하게 지금까지 쓰인 내용을 즉시 fsync한다.
+// 스냅샷을 찍기 전이나 종료 직전처럼 명시적인 내구성 보장이 필요할 때 사용한다.
+func (s *store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.syncLocked()
+}
+
 // [store.Read]
-// 지정 위치(pos)에 저장된 레코드를 반환
-// 레코드가 아직 버퍼에 있을 때를 대비하여 쓰기 버퍼의 내용을 우선 플러시(flush)하여 디스크에 기록
-// 그 다음 읽을 레코드의 바이트 크기를 알아내고 그 만큼의 바이트를 읽어 반환
-func (s *store) Read(pos uint64) ([]byte, error) {
+// 지정 위치(pos)에 저장된 레코드를 반환. pos.FileNo로 어느 물리 파일인지 찾고,
+// 레코드가 아직 쓰기 버퍼에 있을 때를 대비해 먼저 플러시(flush)하여 디스크에 기록한다.
+// 그 다음 읽을 레코드의 바이트 크기를 알아내고 그만큼의 바이트를 읽은 뒤, 같이 저장된
+// crc32c와 비교해 bit-rot이나 찢어진 쓰기로 인한 손상이 없는지 검증한다.
+func (s *store) Read(pos filePos) ([]byte, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if err := s.buf.Flush(); err != nil {
 		return nil, err
 	}
-	size := make([]byte, lenWidth)
-	if _, err := s.File.ReadAt(size, int64(pos)); err != nil {
+	sf, err := s.fileByNo(pos.FileNo)
+	if err != nil {
 		return nil, err
 	}
-	b := make([]byte, enc.Uint64(size))
-	if _, err := s.File.ReadAt(b, int64(pos+lenWidth)); err != nil {
+	off := sf.dataStart + int64(pos.Offset)
+	header := make([]byte, lenWidth+crcWidth)
+	if _, err := sf.file.ReadAt(header, off); err != nil {
 		return nil, err
 	}
+	wantCRC := enc.Uint32(header[lenWidth:])
+	b := make([]byte, enc.Uint64(header[:lenWidth]))
+	if _, err := sf.file.ReadAt(b, off+lenWidth+crcWidth); err != nil {
+		return nil, err
+	}
+	if got := crc32.Checksum(b, crcTable); got != wantCRC {
+		return nil, fmt.Errorf("store: crc 불일치 (pos=%+v): 레코드가 손상됨", pos)
+	}
 	return b, nil
 }
 
+// RecordSize는 pos에 저장된 레코드의 페이로드 크기(길이 프리픽스에 적힌 값)를 읽는다.
+// ReadInto와 짝지어 쓰면, 호출자가 알맞은 크기의 버퍼를 미리 마련해 추가 할당 없이
+// 레코드를 읽어올 수 있다.
+func (s *store) RecordSize(pos filePos) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.buf.Flush(); err != nil {
+		return 0, err
+	}
+	sf, err := s.fileByNo(pos.FileNo)
+	if err != nil {
+		return 0, err
+	}
+	size := make([]byte, lenWidth)
+	if _, err := sf.file.ReadAt(size, sf.dataStart+int64(pos.Offset)); err != nil {
+		return 0, err
+	}
+	return enc.Uint64(size), nil
+}
+
+// ReadInto는 pos에 저장된 레코드의 페이로드를 새로 할당하지 않고 dst에 직접 채운 뒤
+// crc32c를 검증한다. dst는 RecordSize가 리턴한 크기 이상이어야 하며, 실제로 읽은
+// 바이트 수를 리턴한다. segment.ReadRaw가 sync.Pool로 재사용하는 버퍼를 채울 때 이
+// 메서드를 사용해서, 레코드를 읽을 때마다 새 []byte를 할당하는 비용을 없앤다.
+func (s *store) ReadInto(pos filePos, dst []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.buf.Flush(); err != nil {
+		return 0, err
+	}
+	sf, err := s.fileByNo(pos.FileNo)
+	if err != nil {
+		return 0, err
+	}
+	off := sf.dataStart + int64(pos.Offset)
+	header := make([]byte, lenWidth+crcWidth)
+	if _, err := sf.file.ReadAt(header, off); err != nil {
+		return 0, err
+	}
+	recLen := enc.Uint64(header[:lenWidth])
+	wantCRC := enc.Uint32(header[lenWidth:])
+	if uint64(len(dst)) < recLen {
+		return 0, io.ErrShortBuffer
+	}
+	n, err := sf.file.ReadAt(dst[:recLen], off+lenWidth+crcWidth)
+	if err != nil {
+		return n, err
+	}
+	if got := crc32.Checksum(dst[:recLen], crcTable); got != wantCRC {
+		return n, fmt.Errorf("store: crc 불일치 (pos=%+v): 레코드가 손상됨", pos)
+	}
+	return n, nil
+}
+
+func (s *store) fileByNo(no uint32) (*storeFile, error) {
+	if int(no) < len(s.files) && s.files[no].fileNo == no {
+		return s.files[no], nil
+	}
+	for _, sf := range s.files {
+		if sf.fileNo == no {
+			return sf, nil
+		}
+	}
+	return nil, fmt.Errorf("store: 알 수 없는 fileNo %d", no)
+}
+
+// ReadAt은 모든 물리 파일의 레코드 데이터(헤더 제외)를 fileNo 순서로 이어붙인 논리
+// 스트림에서 off 위치부터 len(p)바이트를 읽는다. Raft 스냅샷이 세그먼트 store를
+// 통째로 바이트 스트림으로 복제할 때 사용한다.
 func (s *store) ReadAt(p []byte, off int64) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if err := s.buf.Flush(); err != nil {
 		return 0, err
 	}
-	return s.File.ReadAt(p, off)
+	remaining := off
+	read := 0
+	for _, sf := range s.files {
+		if remaining >= int64(sf.size) {
+			remaining -= int64(sf.size)
+			continue
+		}
+		n, err := sf.file.ReadAt(p[read:], sf.dataStart+remaining)
+		read += n
+		if err != nil && err != io.EOF {
+			return read, err
+		}
+		if read == len(p) {
+			return read, nil
+		}
+		remaining = 0
+	}
+	if read < len(p) {
+		return read, io.EOF
+	}
+	return read, nil
+}
+
+// Size는 모든 물리 파일에 걸친 레코드 데이터의 총량을 리턴한다(세그먼트가 가득
+// 찼는지 판단하는 기준).
+func (s *store) Size() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total uint64
+	for _, sf := range s.files {
+		total += sf.size
+	}
+	return total
+}
+
+// Name은 현재 활성 파일의 경로를 리턴한다. 로깅/디버깅 용도.
+func (s *store) Name() string {
+	return s.files[len(s.files)-1].file.Name()
 }
 
 // [store.Close]
-// 파일을 닫기 전 버퍼의 데이터를 파일에 기록
+// runCommitter를 먼저 멈춰(남은 pending 요청을 모두 커밋시킨 뒤) 파일들을 닫기 전
+// 버퍼의 데이터를 파일에 기록
 func (s *store) Close() error {
+	close(s.appendc)
+	s.wg.Wait()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if err := s.buf.Flush(); err != nil {
 		return err
 	}
-	return s.File.Close()
+	for _, sf := range s.files {
+		if err := sf.file.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveAll은 store를 닫고 이 세그먼트에 속한 물리 파일을 전부 지운다.
+func (s *store) RemoveAll() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+	for _, sf := range s.files {
+		if err := os.Remove(sf.file.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
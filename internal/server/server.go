@@ -12,6 +12,15 @@ import (
 type CommitLog interface {
 	Append(*api.Record) (uint64, error)
 	Read(uint64) (*api.Record, error)
+	// AppendBatch는 여러 레코드를 한 번에 반영해, 레코드마다 따로 write + fsync가
+	// 일어나는 비용 없이 여러 Produce 요청을 하나의 호출로 amortize할 수 있게 한다.
+	AppendBatch([]*api.Record) ([]uint64, error)
+	// Flush는 Sync 모드와 무관하게 지금까지 Append된 내용을 즉시 안정적인 저장소에 반영한다.
+	Flush() error
+	// ReadRaw는 레코드를 api.Record로 역직렬화하지 않고 페이로드 그대로 리턴해,
+	// 팬아웃이 큰 컨슈머 경로에서 역직렬화/재직렬화 비용과 할당을 없앤다. 리턴된
+	// released 함수는 호출부가 다 쓰고 난 뒤 반드시 호출해야 한다.
+	ReadRaw(uint64) ([]byte, func(), error)
 }
 
 // => 이 인터페이스를 통해 CommitLog 인터페이스를 만족하는 어떠한 로그 구현도 사용 가능
@@ -60,6 +69,19 @@ func (s *grpcServer) Produce(ctx context.Context, req *api.ProduceRequest) (
 	return &api.ProduceResponse{Offset: offset}, nil
 }
 
+// [grpcServer.ProduceBatch]
+// 클라이언트가 여러 레코드를 한 번의 RPC로 묶어 보낼 때 처리. 레코드마다 왕복하는
+// 대신 한 번의 호출로 amortize하고, 내부적으로도 AppendBatch를 통해 하나의
+// write + fsync로 묶인다.
+func (s *grpcServer) ProduceBatch(ctx context.Context, req *api.ProduceBatchRequest) (
+	*api.ProduceBatchResponse, error) {
+	offsets, err := s.CommitLog.AppendBatch(req.Records)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ProduceBatchResponse{Offsets: offsets}, nil
+}
+
 // [grpcServer.Consume]
 // 클라이언트가 서버의 로그의 소비를 요청할 때 이를 처리
 func (s *grpcServer) Consume(ctx context.Context, req *api.ConsumeRequest) (
@@ -112,3 +134,42 @@ func (s *grpcServer) ConsumeStream(
 		}
 	}
 }
+
+// [grpcServer.ConsumeStreamRaw]
+// ConsumeStream과 같지만 레코드를 api.Record로 역직렬화하지 않고 페이로드를 그대로
+// 스트리밍한다. 같은 응답 메시지를 반복 재사용해 스트림 전체에서 할당을 한 번으로
+// 줄이고, 페이로드 버퍼는 stream.Send가 끝나는 즉시 풀에 반납한다.
+//
+// grpc-go는 모든 메시지를 HTTP/2 프레임으로 감싸 보내므로, 이 경로 아래에 실제
+// sendfile(2)가 탈 수 있는 순수 TCP 전송은 없다 - store 파일 디스크립트에서 곧바로
+// 커널이 페이지 캐시를 소켓에 흘려보내는 fast path는 이 gRPC 기반 전송 위에서는
+// 실현 불가능하다고 보고 의도적으로 빼뒀다. 이 역직렬화/할당 회피 경로(ReadRaw +
+// 메시지 재사용)가 팬아웃 컨슈머에 대한 요청의 목표를 충족한다고 본다.
+func (s *grpcServer) ConsumeStreamRaw(
+	req *api.ConsumeRequest,
+	stream api.Log_ConsumeStreamRawServer,
+) error {
+	res := &api.ConsumeRawResponse{}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		default:
+			payload, release, err := s.CommitLog.ReadRaw(req.Offset)
+			switch err.(type) {
+			case nil:
+			case api.ErrOffsetOutOfRange:
+				continue
+			default:
+				return err
+			}
+			res.Payload = payload
+			sendErr := stream.Send(res)
+			release()
+			if sendErr != nil {
+				return sendErr
+			}
+			req.Offset++
+		}
+	}
+}